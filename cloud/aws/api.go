@@ -17,24 +17,49 @@ limitations under the License.
 package aws
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
 	"regexp"
 	"sort"
+	"strings"
 	"sync"
+	"time"
 
 	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/credentials/ec2rolecreds"
+	"github.com/aws/aws-sdk-go/aws/credentials/stscreds"
 	"github.com/aws/aws-sdk-go/aws/endpoints"
+	"github.com/aws/aws-sdk-go/aws/request"
 	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/iam"
 	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
 	"github.com/aws/aws-sdk-go/service/sts"
 	"github.com/aws/aws-sdk-go/service/sts/stsiface"
 	"github.com/wallix/awless/graph"
 )
 
+// defaultRegionResolveConcurrency bounds how many GetBucketLocation calls
+// fetch_all_regions_bucket_graph runs at once, so a wide account doesn't
+// hammer the S3 endpoint.
+const defaultRegionResolveConcurrency = 10
+
 var DefaultAMIUsers = []string{"ec2-user", "ubuntu", "centos", "bitnami", "admin", "root"}
 
-func AllRegions() []string {
+// AllRegions lists every known AWS region. config is nil-safe: when it
+// targets a custom S3-compatible endpoint, there's exactly one endpoint to
+// talk to rather than a partition of AWS regions to enumerate, so
+// AllRegions returns nil instead.
+func AllRegions(config *StorageConfig) []string {
+	if config.enabled() {
+		return nil
+	}
+
 	var regions sort.StringSlice
 	partitions := endpoints.DefaultResolver().(endpoints.EnumPartitions).Partitions()
 	for _, p := range partitions {
@@ -46,7 +71,14 @@ func AllRegions() []string {
 	return regions
 }
 
-func IsValidRegion(given string) bool {
+// IsValidRegion reports whether given looks like an AWS region name.
+// config is nil-safe: when it targets a custom S3-compatible endpoint,
+// AWS' regional naming scheme doesn't apply, so any given is accepted.
+func IsValidRegion(given string, config *StorageConfig) bool {
+	if config.enabled() {
+		return true
+	}
+
 	reg, _ := regexp.Compile("^(us|eu|ap|sa|ca)\\-\\w+\\-\\d+$")
 	regChina, _ := regexp.Compile("^cn\\-\\w+\\-\\d+$")
 	regUsGov, _ := regexp.Compile("^us\\-gov\\-\\w+\\-\\d+$")
@@ -58,6 +90,8 @@ type Security interface {
 	stsiface.STSAPI
 	GetUserId() (string, error)
 	GetAccountId() (string, error)
+	AssumedAccountId() (string, error)
+	SourceAccountId() (string, error)
 }
 
 type oncer struct {
@@ -68,14 +102,62 @@ type oncer struct {
 
 type security struct {
 	stsiface.STSAPI
+	sourceSTS      stsiface.STSAPI
+	callerIdentity oncer
 }
 
 func NewSecu(sess *session.Session) Security {
-	return &security{sts.New(sess)}
+	return &security{STSAPI: sts.New(sess)}
+}
+
+// NewSecuWithAssumeRole wraps sess with an stscreds.AssumeRoleProvider for
+// roleArn and returns a Security that calls STS as the assumed role, while
+// still keeping sess's own identity available through SourceAccountId.
+// externalID, mfaSerial and tokenCode are only set on the provider when
+// non-empty.
+func NewSecuWithAssumeRole(sess *session.Session, roleArn, externalID, mfaSerial, tokenCode, sessionName string, duration time.Duration) Security {
+	creds := stscreds.NewCredentials(sess, roleArn, func(p *stscreds.AssumeRoleProvider) {
+		if duration > 0 {
+			p.Duration = duration
+		}
+		if externalID != "" {
+			p.ExternalID = awssdk.String(externalID)
+		}
+		if sessionName != "" {
+			p.RoleSessionName = sessionName
+		}
+		if mfaSerial != "" {
+			p.SerialNumber = awssdk.String(mfaSerial)
+		}
+		if tokenCode != "" {
+			p.TokenCode = awssdk.String(tokenCode)
+		}
+	})
+	assumedSess := sess.Copy(&awssdk.Config{Credentials: creds})
+	return &security{STSAPI: sts.New(assumedSess), sourceSTS: sts.New(sess)}
+}
+
+// NewSecuWithWebIdentity wraps sess with an stscreds.WebIdentityRoleProvider
+// that reads a signed OIDC token from tokenFile and exchanges it for
+// temporary credentials scoped to roleArn.
+func NewSecuWithWebIdentity(sess *session.Session, roleArn, tokenFile, sessionName string) Security {
+	creds := stscreds.NewWebIdentityCredentials(sess, roleArn, sessionName, tokenFile)
+	assumedSess := sess.Copy(&awssdk.Config{Credentials: creds})
+	return &security{STSAPI: sts.New(assumedSess), sourceSTS: sts.New(sess)}
+}
+
+func (s *security) getCallerIdentity() (*sts.GetCallerIdentityOutput, error) {
+	s.callerIdentity.Do(func() {
+		s.callerIdentity.result, s.callerIdentity.err = s.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	})
+	if s.callerIdentity.err != nil {
+		return nil, s.callerIdentity.err
+	}
+	return s.callerIdentity.result.(*sts.GetCallerIdentityOutput), nil
 }
 
 func (s *security) GetUserId() (string, error) {
-	output, err := s.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	output, err := s.getCallerIdentity()
 	if err != nil {
 		return "", err
 	}
@@ -83,17 +165,297 @@ func (s *security) GetUserId() (string, error) {
 }
 
 func (s *security) GetAccountId() (string, error) {
-	output, err := s.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	output, err := s.getCallerIdentity()
 	if err != nil {
 		return "", err
 	}
 	return awssdk.StringValue(output.Account), nil
 }
 
-func (s *Access) fetch_all_user_graph() (*graph.Graph, []*iam.UserDetail, error) {
+// AssumedAccountId is an alias of GetAccountId: the account behind s's own
+// STSAPI, i.e. the assumed role/web identity when NewSecuWithAssumeRole or
+// NewSecuWithWebIdentity built s, or the base identity otherwise.
+func (s *security) AssumedAccountId() (string, error) {
+	return s.GetAccountId()
+}
+
+// SourceAccountId calls GetCallerIdentity against sourceSTS, the base
+// session's own STS client, falling back to GetAccountId when s wasn't
+// built from a role assumption (sourceSTS is nil).
+func (s *security) SourceAccountId() (string, error) {
+	if s.sourceSTS == nil {
+		return s.GetAccountId()
+	}
+	output, err := s.sourceSTS.GetCallerIdentity(&sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	return awssdk.StringValue(output.Account), nil
+}
+
+// CredentialProvider turns a scheme-prefixed profile URI (e.g.
+// "iam-ibm://<api-key>", "sso://<profile>") into an AWS session. It's
+// looked up by scheme in the registry populated by RegisterCredentialProvider
+// and consulted through the package-level ResolveSession.
+type CredentialProvider interface {
+	Scheme() string
+	ResolveSession(profileURI string) (*session.Session, error)
+}
+
+var credentialProviders = struct {
+	sync.RWMutex
+	byScheme map[string]CredentialProvider
+}{byScheme: make(map[string]CredentialProvider)}
+
+// RegisterCredentialProvider adds p to the registry ResolveSession
+// consults, keyed by p.Scheme().
+func RegisterCredentialProvider(p CredentialProvider) {
+	credentialProviders.Lock()
+	defer credentialProviders.Unlock()
+	credentialProviders.byScheme[p.Scheme()] = p
+}
+
+// ResolveSession looks up the CredentialProvider registered for
+// profileURI's scheme and delegates to it.
+func ResolveSession(profileURI string) (*session.Session, error) {
+	scheme := profileURIScheme(profileURI)
+
+	credentialProviders.RLock()
+	provider, ok := credentialProviders.byScheme[scheme]
+	credentialProviders.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no credential provider registered for scheme %q", scheme)
+	}
+
+	return provider.ResolveSession(profileURI)
+}
+
+// NewSecuFromProfileURI resolves profileURI through the registered
+// CredentialProvider for its scheme before delegating to NewSecu.
+func NewSecuFromProfileURI(profileURI string) (Security, error) {
+	sess, err := ResolveSession(profileURI)
+	if err != nil {
+		return nil, err
+	}
+	return NewSecu(sess), nil
+}
+
+func profileURIScheme(profileURI string) string {
+	if i := strings.Index(profileURI, "://"); i >= 0 {
+		return profileURI[:i+3]
+	}
+	return profileURI
+}
+
+const ibmIAMTokenEndpoint = "https://iam.cloud.ibm.com/identity/token"
+
+type ibmIAMCredentialProvider struct{}
+
+func (p *ibmIAMCredentialProvider) Scheme() string { return "iam-ibm://" }
+
+func (p *ibmIAMCredentialProvider) ResolveSession(profileURI string) (*session.Session, error) {
+	apiKey := strings.TrimPrefix(profileURI, p.Scheme())
+	creds := credentials.NewCredentials(&ibmIAMProvider{apiKey: apiKey})
+
+	sess, err := session.NewSession(&awssdk.Config{Credentials: creds})
+	if err != nil {
+		return nil, err
+	}
+
+	// IBM COS authenticates with "Authorization: Bearer <token>", not AWS
+	// SigV4, so the SDK's own signer (which would sign with an empty
+	// secret key and produce a bad signature) is swapped out for a
+	// handler that sets the header directly from the token this
+	// credentials.Provider retrieved.
+	sess.Handlers.Sign.Clear()
+	sess.Handlers.Sign.PushBack(func(r *request.Request) {
+		val, err := creds.Get()
+		if err != nil {
+			r.Error = err
+			return
+		}
+		r.HTTPRequest.Header.Set("Authorization", "Bearer "+val.AccessKeyID)
+	})
+
+	return sess, nil
+}
+
+// ibmIAMProvider exchanges an IBM Cloud API key for a short-lived IAM
+// access token via an OAuth-style token POST, and satisfies
+// credentials.Provider so the token and its expiry are cached and
+// refreshed by the credentials.Credentials wrapper like any other
+// credential source. The token itself is carried in credentials.Value's
+// AccessKeyID field purely as a transport for the bearer-auth handler
+// installed in ResolveSession — it is never used for SigV4 signing.
+type ibmIAMProvider struct {
+	apiKey     string
+	expiration time.Time
+}
+
+func (p *ibmIAMProvider) Retrieve() (credentials.Value, error) {
+	form := url.Values{}
+	form.Set("grant_type", "urn:ibm:params:oauth:grant-type:apikey")
+	form.Set("apikey", p.apiKey)
+
+	req, err := http.NewRequest(http.MethodPost, ibmIAMTokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return credentials.Value{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return credentials.Value{}, fmt.Errorf("ibm iam token exchange failed: %s", resp.Status)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		Expiration  int64  `json:"expiration"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return credentials.Value{}, err
+	}
+
+	p.expiration = time.Unix(token.Expiration, 0)
+	return credentials.Value{
+		AccessKeyID:  token.AccessToken,
+		ProviderName: "IBMIAMProvider",
+	}, nil
+}
+
+func (p *ibmIAMProvider) IsExpired() bool {
+	return time.Now().After(p.expiration.Add(-time.Minute))
+}
+
+type processCredentialProvider struct{}
+
+func (p *processCredentialProvider) Scheme() string { return "process://" }
+
+func (p *processCredentialProvider) ResolveSession(profileURI string) (*session.Session, error) {
+	cmdLine := strings.TrimPrefix(profileURI, p.Scheme())
+	creds := credentials.NewCredentials(&processCredentialsProvider{cmdLine: cmdLine})
+	return session.NewSession(&awssdk.Config{Credentials: creds})
+}
+
+// processCredentialsProvider shells out to an external command and parses
+// its JSON {AccessKeyId, SecretAccessKey, SessionToken, Expiration}
+// response, mirroring the AWS CLI's credential_process convention.
+type processCredentialsProvider struct {
+	cmdLine    string
+	expiration time.Time
+}
+
+func (p *processCredentialsProvider) Retrieve() (credentials.Value, error) {
+	parts := strings.Fields(p.cmdLine)
+	if len(parts) == 0 {
+		return credentials.Value{}, fmt.Errorf("empty credential process command")
+	}
+
+	out, err := exec.Command(parts[0], parts[1:]...).Output()
+	if err != nil {
+		return credentials.Value{}, fmt.Errorf("run credential process: %s", err)
+	}
+
+	var resp struct {
+		AccessKeyId     string
+		SecretAccessKey string
+		SessionToken    string
+		Expiration      time.Time
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return credentials.Value{}, fmt.Errorf("parse credential process output: %s", err)
+	}
+
+	p.expiration = resp.Expiration
+	return credentials.Value{
+		AccessKeyID:     resp.AccessKeyId,
+		SecretAccessKey: resp.SecretAccessKey,
+		SessionToken:    resp.SessionToken,
+		ProviderName:    "ProcessProvider",
+	}, nil
+}
+
+func (p *processCredentialsProvider) IsExpired() bool {
+	return !p.expiration.IsZero() && time.Now().After(p.expiration)
+}
+
+type ec2MetadataCredentialProvider struct{}
+
+func (p *ec2MetadataCredentialProvider) Scheme() string { return "ec2-metadata://" }
+
+func (p *ec2MetadataCredentialProvider) ResolveSession(profileURI string) (*session.Session, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, err
+	}
+	return sess.Copy(&awssdk.Config{Credentials: ec2rolecreds.NewCredentials(sess)}), nil
+}
+
+type ssoCredentialProvider struct{}
+
+func (p *ssoCredentialProvider) Scheme() string { return "sso://" }
+
+func (p *ssoCredentialProvider) ResolveSession(profileURI string) (*session.Session, error) {
+	profile := strings.TrimPrefix(profileURI, p.Scheme())
+	return session.NewSessionWithOptions(session.Options{
+		Profile:           profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
+
+type envCredentialProvider struct{}
+
+func (p *envCredentialProvider) Scheme() string { return "env://" }
+
+func (p *envCredentialProvider) ResolveSession(profileURI string) (*session.Session, error) {
+	return session.NewSession(&awssdk.Config{Credentials: credentials.NewEnvCredentials()})
+}
+
+func init() {
+	RegisterCredentialProvider(&ibmIAMCredentialProvider{})
+	RegisterCredentialProvider(&processCredentialProvider{})
+	RegisterCredentialProvider(&ec2MetadataCredentialProvider{})
+	RegisterCredentialProvider(&ssoCredentialProvider{})
+	RegisterCredentialProvider(&envCredentialProvider{})
+}
+
+// NewAccessFromProfileURI resolves a session through the registered
+// CredentialProvider for profileURI's scheme and builds an Access on top
+// of it, mirroring NewSecuFromProfileURI for the Access fetchers.
+func NewAccessFromProfileURI(profileURI string) (*Access, error) {
+	sess, err := ResolveSession(profileURI)
+	if err != nil {
+		return nil, err
+	}
+	return &Access{IAMAPI: iam.New(sess)}, nil
+}
+
+// fetch_all_user_graph inventories IAM users the same way fetch_all_bucket_graph
+// inventories buckets, and additionally tags every resource with
+// SourceAccountId (the identity awless itself is calling AWS as) and
+// AssumedAccountId (the account fetched through, when secu was built with
+// NewSecuWithAssumeRole/NewSecuWithWebIdentity — otherwise identical to
+// SourceAccountId), so resources pulled through a cross-account assumed
+// role can still be traced back to both identities.
+func (s *Access) fetch_all_user_graph(secu Security) (*graph.Graph, []*iam.UserDetail, error) {
 	g := graph.NewGraph()
 	var userDetails []*iam.UserDetail
 
+	sourceAccountId, err := secu.SourceAccountId()
+	if err != nil {
+		return g, userDetails, err
+	}
+	assumedAccountId, err := secu.AssumedAccountId()
+	if err != nil {
+		return g, userDetails, err
+	}
+
 	var wg sync.WaitGroup
 	errc := make(chan error)
 
@@ -118,6 +480,8 @@ func (s *Access) fetch_all_user_graph() (*graph.Graph, []*iam.UserDetail, error)
 				errc <- err
 				return
 			}
+			res.Properties["SourceAccountId"] = sourceAccountId
+			res.Properties["AssumedAccountId"] = assumedAccountId
 			g.AddResource(res)
 		}
 	}()
@@ -138,6 +502,8 @@ func (s *Access) fetch_all_user_graph() (*graph.Graph, []*iam.UserDetail, error)
 				errc <- err
 				return
 			}
+			res.Properties["SourceAccountId"] = sourceAccountId
+			res.Properties["AssumedAccountId"] = assumedAccountId
 			g.AddResource(res)
 		}
 	}()
@@ -156,6 +522,83 @@ func (s *Access) fetch_all_user_graph() (*graph.Graph, []*iam.UserDetail, error)
 	return g, userDetails, nil
 }
 
+// StorageConfig overrides the session a Storage is built on: a custom
+// Endpoint and PathStyle addressing for S3-compatible servers (MinIO, IBM
+// Cloud Object Storage, Ceph RGW, ...), and an optional static
+// AccessKey/SecretKey pair in place of the shared AWS credential chain.
+type StorageConfig struct {
+	Endpoint  string
+	PathStyle bool
+	AccessKey string
+	SecretKey string
+}
+
+func (c *StorageConfig) enabled() bool {
+	return c != nil && c.Endpoint != ""
+}
+
+// NewStorageConfig builds a StorageConfig for an S3-compatible endpoint,
+// authenticated with a static access/secret key pair rather than the
+// shared AWS credential chain.
+func NewStorageConfig(endpoint string, pathStyle bool, accessKey, secretKey string) *StorageConfig {
+	return &StorageConfig{
+		Endpoint:  endpoint,
+		PathStyle: pathStyle,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	}
+}
+
+// NewStorage builds a Storage client for region. When config targets an
+// S3-compatible endpoint, the session is rebuilt with config.Endpoint,
+// config.PathStyle and (if set) a static credentials.StaticProvider in
+// place of sess's own credential chain, before the S3 client is created on
+// top of it.
+func NewStorage(sess *session.Session, region string, config *StorageConfig) *Storage {
+	storageSess := sess
+	if config.enabled() {
+		awsCfg := awssdk.NewConfig().
+			WithRegion(region).
+			WithEndpoint(config.Endpoint).
+			WithS3ForcePathStyle(config.PathStyle)
+		if config.AccessKey != "" || config.SecretKey != "" {
+			awsCfg = awsCfg.WithCredentials(credentials.NewStaticCredentials(config.AccessKey, config.SecretKey, ""))
+		}
+		storageSess = sess.Copy(awsCfg)
+	}
+
+	return &Storage{
+		S3API:  s3.New(storageSess),
+		sess:   storageSess,
+		region: region,
+		config: config,
+	}
+}
+
+// regionalClient builds an s3iface.S3API client targeting region, honoring
+// any custom endpoint/path-style/static credentials in s.config the same
+// way NewStorage does — so per-region fan-outs (see
+// fetch_all_regions_storageobject_graph) don't silently fall back to real
+// AWS endpoints for S3-compatible deployments.
+func (s *Storage) regionalClient(region string) s3iface.S3API {
+	awsCfg := awssdk.NewConfig().WithRegion(region)
+	if s.config.enabled() {
+		awsCfg = awsCfg.WithEndpoint(s.config.Endpoint).WithS3ForcePathStyle(s.config.PathStyle)
+	}
+	return s3.New(s.sess, awsCfg)
+}
+
+// NewStorageFromProfileURI resolves a session through the registered
+// CredentialProvider for profileURI's scheme before delegating to
+// NewStorage, mirroring NewSecuFromProfileURI for the Storage fetchers.
+func NewStorageFromProfileURI(profileURI, region string, config *StorageConfig) (*Storage, error) {
+	sess, err := ResolveSession(profileURI)
+	if err != nil {
+		return nil, err
+	}
+	return NewStorage(sess, region, config), nil
+}
+
 func (s *Storage) fetch_all_bucket_graph() (*graph.Graph, []*s3.Bucket, error) {
 	g := graph.NewGraph()
 	var buckets []*s3.Bucket
@@ -170,43 +613,601 @@ func (s *Storage) fetch_all_bucket_graph() (*graph.Graph, []*s3.Bucket, error) {
 		if err != nil {
 			return fmt.Errorf("build resource for bucket `%s`: %s", awssdk.StringValue(b.Name), err)
 		}
+
+		for _, fetchSub := range bucketSubResourceFetchers {
+			if err := fetchSub(s, b, g); err != nil {
+				return fmt.Errorf("fetch sub-resources for bucket `%s`: %s", awssdk.StringValue(b.Name), err)
+			}
+		}
+
 		return nil
 	})
 	return g, buckets, err
 }
 
-func (s *Storage) fetch_all_storageobject_graph() (*graph.Graph, []*s3.Object, error) {
+// bucketSubResourceFetchers lists every bucket-scoped configuration
+// awless promotes to its own graph resource kind, run for each bucket
+// alongside fetch_all_bucket_graph's own foreach_bucket_parallel fan-out.
+var bucketSubResourceFetchers = []func(*Storage, *s3.Bucket, *graph.Graph) error{
+	(*Storage).fetchBucketPolicy,
+	(*Storage).fetchBucketACL,
+	(*Storage).fetchBucketCORS,
+	(*Storage).fetchBucketLifecycle,
+	(*Storage).fetchBucketEncryption,
+	(*Storage).fetchBucketVersioning,
+}
+
+// awsPolicyDocument mirrors the JSON shape of an S3 bucket policy, so its
+// statements can be flattened into awless-filterable properties (e.g.
+// Principal=="*" for a security audit).
+type awsPolicyDocument struct {
+	Version   string               `json:"Version"`
+	Statement []awsPolicyStatement `json:"Statement"`
+}
+
+type awsPolicyStatement struct {
+	Effect    string      `json:"Effect"`
+	Principal interface{} `json:"Principal"`
+	Action    interface{} `json:"Action"`
+	Resource  interface{} `json:"Resource"`
+	Condition interface{} `json:"Condition"`
+}
+
+// BucketPolicy is the graph resource kind for a parsed S3 bucket policy
+// document. Unreadable is set when awless was denied access to the
+// policy rather than the bucket simply not having one — the zero-value
+// Statements in that case says nothing about whether a policy exists.
+type BucketPolicy struct {
+	Statements []awsPolicyStatement
+	Unreadable bool
+}
+
+// BucketACL is the graph resource kind for a bucket's access control list.
+type BucketACL struct {
+	Owner      *s3.Owner
+	Grants     []*s3.Grant
+	Unreadable bool
+}
+
+// BucketCORS is the graph resource kind for a bucket's CORS configuration.
+type BucketCORS struct {
+	Rules      []*s3.CORSRule
+	Unreadable bool
+}
+
+// BucketLifecycle is the graph resource kind for a bucket's lifecycle
+// configuration.
+type BucketLifecycle struct {
+	Rules      []*s3.LifecycleRule
+	Unreadable bool
+}
+
+// BucketEncryption is the graph resource kind for a bucket's default
+// server-side encryption configuration.
+type BucketEncryption struct {
+	Rules      []*s3.ServerSideEncryptionRule
+	Unreadable bool
+}
+
+// BucketVersioning is the graph resource kind for a bucket's versioning
+// configuration.
+type BucketVersioning struct {
+	Status     string
+	MFADelete  string
+	Unreadable bool
+}
+
+func (s *Storage) fetchBucketPolicy(bucket *s3.Bucket, g *graph.Graph) error {
+	out, err := s.GetBucketPolicy(&s3.GetBucketPolicyInput{Bucket: bucket.Name})
+	if err != nil {
+		if isAbsentBucketSubResourceErr(err) {
+			return nil
+		}
+		if isAccessDeniedBucketSubResourceErr(err) {
+			return addBucketSubResource(g, bucket, &BucketPolicy{Unreadable: true})
+		}
+		return err
+	}
+
+	var doc awsPolicyDocument
+	if err := json.Unmarshal([]byte(awssdk.StringValue(out.Policy)), &doc); err != nil {
+		return fmt.Errorf("parse bucket policy document: %s", err)
+	}
+
+	return addBucketSubResource(g, bucket, &BucketPolicy{Statements: doc.Statement})
+}
+
+func (s *Storage) fetchBucketACL(bucket *s3.Bucket, g *graph.Graph) error {
+	out, err := s.GetBucketAcl(&s3.GetBucketAclInput{Bucket: bucket.Name})
+	if err != nil {
+		if isAbsentBucketSubResourceErr(err) {
+			return nil
+		}
+		if isAccessDeniedBucketSubResourceErr(err) {
+			return addBucketSubResource(g, bucket, &BucketACL{Unreadable: true})
+		}
+		return err
+	}
+	return addBucketSubResource(g, bucket, &BucketACL{Owner: out.Owner, Grants: out.Grants})
+}
+
+func (s *Storage) fetchBucketCORS(bucket *s3.Bucket, g *graph.Graph) error {
+	out, err := s.GetBucketCors(&s3.GetBucketCorsInput{Bucket: bucket.Name})
+	if err != nil {
+		if isAbsentBucketSubResourceErr(err) {
+			return nil
+		}
+		if isAccessDeniedBucketSubResourceErr(err) {
+			return addBucketSubResource(g, bucket, &BucketCORS{Unreadable: true})
+		}
+		return err
+	}
+	return addBucketSubResource(g, bucket, &BucketCORS{Rules: out.CORSRules})
+}
+
+func (s *Storage) fetchBucketLifecycle(bucket *s3.Bucket, g *graph.Graph) error {
+	out, err := s.GetBucketLifecycleConfiguration(&s3.GetBucketLifecycleConfigurationInput{Bucket: bucket.Name})
+	if err != nil {
+		if isAbsentBucketSubResourceErr(err) {
+			return nil
+		}
+		if isAccessDeniedBucketSubResourceErr(err) {
+			return addBucketSubResource(g, bucket, &BucketLifecycle{Unreadable: true})
+		}
+		return err
+	}
+	return addBucketSubResource(g, bucket, &BucketLifecycle{Rules: out.Rules})
+}
+
+func (s *Storage) fetchBucketEncryption(bucket *s3.Bucket, g *graph.Graph) error {
+	out, err := s.GetBucketEncryption(&s3.GetBucketEncryptionInput{Bucket: bucket.Name})
+	if err != nil {
+		if isAbsentBucketSubResourceErr(err) {
+			return nil
+		}
+		if isAccessDeniedBucketSubResourceErr(err) {
+			return addBucketSubResource(g, bucket, &BucketEncryption{Unreadable: true})
+		}
+		return err
+	}
+	var rules []*s3.ServerSideEncryptionRule
+	if out.ServerSideEncryptionConfiguration != nil {
+		rules = out.ServerSideEncryptionConfiguration.Rules
+	}
+	return addBucketSubResource(g, bucket, &BucketEncryption{Rules: rules})
+}
+
+func (s *Storage) fetchBucketVersioning(bucket *s3.Bucket, g *graph.Graph) error {
+	out, err := s.GetBucketVersioning(&s3.GetBucketVersioningInput{Bucket: bucket.Name})
+	if err != nil {
+		if isAbsentBucketSubResourceErr(err) {
+			return nil
+		}
+		if isAccessDeniedBucketSubResourceErr(err) {
+			return addBucketSubResource(g, bucket, &BucketVersioning{Unreadable: true})
+		}
+		return err
+	}
+	return addBucketSubResource(g, bucket, &BucketVersioning{
+		Status:    awssdk.StringValue(out.Status),
+		MFADelete: awssdk.StringValue(out.MFADelete),
+	})
+}
+
+func addBucketSubResource(g *graph.Graph, bucket *s3.Bucket, awsOutput interface{}) error {
+	res, err := newResource(awsOutput)
+	if err != nil {
+		return err
+	}
+	res.Properties["BucketName"] = awssdk.StringValue(bucket.Name)
+	g.AddResource(res)
+
+	parent, err := initResource(bucket)
+	if err != nil {
+		return err
+	}
+	g.AddParentRelation(parent, res)
+	return nil
+}
+
+// bucketSubResourceAbsentCodes are the AWS error codes that mean a bucket
+// simply has no such configuration — the bucket itself should still show
+// up in the graph, just without that one sub-resource.
+var bucketSubResourceAbsentCodes = map[string]bool{
+	"NoSuchBucketPolicy":                             true,
+	"NoSuchCORSConfiguration":                        true,
+	"NoSuchLifecycleConfiguration":                   true,
+	"ServerSideEncryptionConfigurationNotFoundError": true,
+}
+
+// bucketSubResourceAccessDeniedCodes are the AWS error codes that mean
+// awless isn't allowed to read the configuration (common on shared/
+// cross-account buckets). This is deliberately kept separate from
+// bucketSubResourceAbsentCodes: for a security-audit tool, "couldn't
+// check" and "confirmed absent" are not the same fact, and folding them
+// together would report an unreadable policy/encryption setting as if it
+// were known to be missing.
+var bucketSubResourceAccessDeniedCodes = map[string]bool{
+	"AccessDenied":          true,
+	"AccessDeniedException": true,
+}
+
+func isAbsentBucketSubResourceErr(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && bucketSubResourceAbsentCodes[awsErr.Code()]
+}
+
+func isAccessDeniedBucketSubResourceErr(err error) bool {
+	awsErr, ok := err.(awserr.Error)
+	return ok && bucketSubResourceAccessDeniedCodes[awsErr.Code()]
+}
+
+// StorageFetchOptions narrows a storage object fetch down to the objects
+// callers actually need, so accounts with millions of keys don't have to
+// be paged through in full on every run.
+type StorageFetchOptions struct {
+	Prefix           string
+	Delimiter        string
+	MaxKeysPerBucket int64
+	SinceModified    time.Time
+	IncludeVersions  bool
+}
+
+func (o StorageFetchOptions) keepsGoing(count int64) bool {
+	return o.MaxKeysPerBucket <= 0 || count < o.MaxKeysPerBucket
+}
+
+func (o StorageFetchOptions) skips(lastModified *time.Time) bool {
+	return !o.SinceModified.IsZero() && lastModified != nil && lastModified.Before(o.SinceModified)
+}
+
+func (s *Storage) fetch_all_storageobject_graph(opts StorageFetchOptions) (*graph.Graph, []*s3.Object, error) {
 	g := graph.NewGraph()
 	var cloudResources []*s3.Object
 
 	err := s.foreach_bucket_parallel(func(b *s3.Bucket) error {
-		return s.fetchObjectsForBucket(b, g)
+		return s.fetchObjectsForBucket(b, g, opts)
 	})
 
 	return g, cloudResources, err
 }
 
-func (s *Storage) fetchObjectsForBucket(bucket *s3.Bucket, g *graph.Graph) error {
-	out, err := s.ListObjects(&s3.ListObjectsInput{Bucket: bucket.Name})
+func (s *Storage) fetchObjectsForBucket(bucket *s3.Bucket, g *graph.Graph, opts StorageFetchOptions) error {
+	return fetchObjectsForBucketIn(s.S3API, bucket, g, opts)
+}
+
+// fetchObjectsForBucketIn pages through a bucket's objects (or, with
+// opts.IncludeVersions, every object version) through the given S3 client,
+// letting callers target the client for a specific region instead of the
+// Storage's own (see fetch_all_regions_storageobject_graph).
+func fetchObjectsForBucketIn(api s3iface.S3API, bucket *s3.Bucket, g *graph.Graph, opts StorageFetchOptions) error {
+	if opts.IncludeVersions {
+		return fetchObjectVersionsForBucketIn(api, bucket, g, opts)
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: bucket.Name}
+	if opts.Prefix != "" {
+		input.Prefix = awssdk.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = awssdk.String(opts.Delimiter)
+	}
+
+	var count int64
+	var addErr error
+	err := api.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, output := range page.Contents {
+			if opts.skips(output.LastModified) {
+				continue
+			}
+			if addErr = addBucketObjectResource(g, bucket, output); addErr != nil {
+				return false
+			}
+			count++
+			if !opts.keepsGoing(count) {
+				return false
+			}
+		}
+		return opts.keepsGoing(count)
+	})
+	if addErr != nil {
+		return addErr
+	}
+	return err
+}
+
+func fetchObjectVersionsForBucketIn(api s3iface.S3API, bucket *s3.Bucket, g *graph.Graph, opts StorageFetchOptions) error {
+	input := &s3.ListObjectVersionsInput{Bucket: bucket.Name}
+	if opts.Prefix != "" {
+		input.Prefix = awssdk.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = awssdk.String(opts.Delimiter)
+	}
+
+	var count int64
+	var addErr error
+	err := api.ListObjectVersionsPages(input, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, version := range page.Versions {
+			if opts.skips(version.LastModified) {
+				continue
+			}
+			if addErr = addBucketObjectResource(g, bucket, version); addErr != nil {
+				return false
+			}
+			count++
+			if !opts.keepsGoing(count) {
+				return false
+			}
+		}
+		return opts.keepsGoing(count)
+	})
+	if addErr != nil {
+		return addErr
+	}
+	return err
+}
+
+func addBucketObjectResource(g *graph.Graph, bucket *s3.Bucket, awsObject interface{}) error {
+	res, err := newResource(awsObject)
+	if err != nil {
+		return err
+	}
+	res.Properties["BucketName"] = awssdk.StringValue(bucket.Name)
+	g.AddResource(res)
+
+	parent, err := initResource(bucket)
 	if err != nil {
 		return err
 	}
+	g.AddParentRelation(parent, res)
+	return nil
+}
+
+// fetchObjectsForBucketStream is fetchObjectsForBucket's pagination loop
+// with the per-page resources sent to out instead of added to a *graph.Graph,
+// so a caller can range over out while later pages are still being fetched.
+// With opts.IncludeVersions it dispatches to
+// fetchObjectVersionsForBucketStream, mirroring fetchObjectsForBucketIn.
+func (s *Storage) fetchObjectsForBucketStream(bucket *s3.Bucket, opts StorageFetchOptions, out chan<- *graph.Resource) error {
+	if opts.IncludeVersions {
+		return s.fetchObjectVersionsForBucketStream(bucket, opts, out)
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: bucket.Name}
+	if opts.Prefix != "" {
+		input.Prefix = awssdk.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = awssdk.String(opts.Delimiter)
+	}
+
+	var count int64
+	var addErr error
+	err := s.ListObjectsV2Pages(input, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, output := range page.Contents {
+			if opts.skips(output.LastModified) {
+				continue
+			}
+			res, err := newResource(output)
+			if err != nil {
+				addErr = err
+				return false
+			}
+			res.Properties["BucketName"] = awssdk.StringValue(bucket.Name)
+			out <- res
+			count++
+			if !opts.keepsGoing(count) {
+				return false
+			}
+		}
+		return opts.keepsGoing(count)
+	})
+	if addErr != nil {
+		return addErr
+	}
+	return err
+}
+
+// fetchObjectVersionsForBucketStream is fetchObjectVersionsForBucketIn's
+// pagination loop with the per-page resources sent to out instead of added
+// to a *graph.Graph, used by fetchObjectsForBucketStream when
+// opts.IncludeVersions is set.
+func (s *Storage) fetchObjectVersionsForBucketStream(bucket *s3.Bucket, opts StorageFetchOptions, out chan<- *graph.Resource) error {
+	input := &s3.ListObjectVersionsInput{Bucket: bucket.Name}
+	if opts.Prefix != "" {
+		input.Prefix = awssdk.String(opts.Prefix)
+	}
+	if opts.Delimiter != "" {
+		input.Delimiter = awssdk.String(opts.Delimiter)
+	}
+
+	var count int64
+	var addErr error
+	err := s.ListObjectVersionsPages(input, func(page *s3.ListObjectVersionsOutput, lastPage bool) bool {
+		for _, version := range page.Versions {
+			if opts.skips(version.LastModified) {
+				continue
+			}
+			res, err := newResource(version)
+			if err != nil {
+				addErr = err
+				return false
+			}
+			res.Properties["BucketName"] = awssdk.StringValue(bucket.Name)
+			out <- res
+			count++
+			if !opts.keepsGoing(count) {
+				return false
+			}
+		}
+		return opts.keepsGoing(count)
+	})
+	if addErr != nil {
+		return addErr
+	}
+	return err
+}
+
+// fetch_all_regions_bucket_graph inventories every bucket in the account
+// regardless of the region the Storage client was built for, resolving
+// each bucket's real region via GetBucketLocation over a bounded worker
+// pool and stamping the result onto each resource's Region property. The
+// single-region fetch_all_bucket_graph remains for callers that pass a
+// specific --aws-region.
+func (s *Storage) fetch_all_regions_bucket_graph() (*graph.Graph, []*s3.Bucket, error) {
+	if s.config.enabled() {
+		// S3-compatible endpoints (MinIO, IBM COS, Ceph RGW, ...) don't
+		// have AWS region semantics to discover: GetBucketLocation isn't
+		// meaningful against them, so there's nothing to fan out across
+		// regions for. Fall back to the single-endpoint fetch.
+		return s.fetch_all_bucket_graph()
+	}
+
+	g := graph.NewGraph()
+	var buckets []*s3.Bucket
+
+	out, err := s.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return g, buckets, err
+	}
+
+	regions, err := s.resolveBucketRegions(out.Buckets, defaultRegionResolveConcurrency)
+	if err != nil {
+		return g, buckets, err
+	}
 
-	for _, output := range out.Contents {
-		res, err := newResource(output)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	errc := make(chan error, len(out.Buckets))
+
+	for _, b := range out.Buckets {
+		wg.Add(1)
+		go func(b *s3.Bucket) {
+			defer wg.Done()
+			res, err := newResource(b)
+			if err != nil {
+				errc <- fmt.Errorf("build resource for bucket `%s`: %s", awssdk.StringValue(b.Name), err)
+				return
+			}
+			res.Properties["Region"] = regions[awssdk.StringValue(b.Name)]
+
+			mu.Lock()
+			buckets = append(buckets, b)
+			g.AddResource(res)
+			mu.Unlock()
+		}(b)
+	}
+	wg.Wait()
+	close(errc)
+
+	for err := range errc {
 		if err != nil {
-			return err
+			return g, buckets, err
 		}
-		res.Properties["BucketName"] = awssdk.StringValue(bucket.Name)
-		g.AddResource(res)
-		parent, err := initResource(bucket)
+	}
+
+	return g, buckets, nil
+}
+
+// fetch_all_regions_storageobject_graph mirrors fetch_all_regions_bucket_graph
+// for objects: since ListObjects must target a bucket's home region, it
+// spins up a per-region S3 client via regionalClient for each bucket and
+// adds every bucket's objects to one shared graph. Unlike
+// fetch_all_regions_bucket_graph, it sets no Region property on the
+// resources it adds.
+func (s *Storage) fetch_all_regions_storageobject_graph(opts StorageFetchOptions) (*graph.Graph, []*s3.Object, error) {
+	if s.config.enabled() {
+		// Same reasoning as fetch_all_regions_bucket_graph: a custom
+		// endpoint has one region, not many, so there's nothing to
+		// resolve or fan out across.
+		return s.fetch_all_storageobject_graph(opts)
+	}
+
+	g := graph.NewGraph()
+	var cloudResources []*s3.Object
+
+	out, err := s.ListBuckets(&s3.ListBucketsInput{})
+	if err != nil {
+		return g, cloudResources, err
+	}
+
+	regions, err := s.resolveBucketRegions(out.Buckets, defaultRegionResolveConcurrency)
+	if err != nil {
+		return g, cloudResources, err
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, defaultRegionResolveConcurrency)
+	errc := make(chan error, len(out.Buckets))
+
+	for _, b := range out.Buckets {
+		wg.Add(1)
+		go func(b *s3.Bucket) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			region := regions[awssdk.StringValue(b.Name)]
+			regionalClient := s.regionalClient(region)
+			if err := fetchObjectsForBucketIn(regionalClient, b, g, opts); err != nil {
+				errc <- err
+			}
+		}(b)
+	}
+	wg.Wait()
+	close(errc)
+
+	for err := range errc {
 		if err != nil {
-			return err
+			return g, cloudResources, err
 		}
-		g.AddParentRelation(parent, res)
 	}
 
-	return nil
+	return g, cloudResources, nil
+}
+
+// resolveBucketRegions resolves each bucket's real region via
+// GetBucketLocation, running at most concurrency calls at once.
+func (s *Storage) resolveBucketRegions(buckets []*s3.Bucket, concurrency int) (map[string]string, error) {
+	regions := make(map[string]string, len(buckets))
+	var mu sync.Mutex
+	sem := make(chan struct{}, concurrency)
+	errc := make(chan error, len(buckets))
+	var wg sync.WaitGroup
+
+	for _, b := range buckets {
+		wg.Add(1)
+		go func(b *s3.Bucket) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			loc, err := s.GetBucketLocation(&s3.GetBucketLocationInput{Bucket: b.Name})
+			if err != nil {
+				errc <- err
+				return
+			}
+			region := awssdk.StringValue(loc.LocationConstraint)
+			if region == "" {
+				region = "us-east-1"
+			}
+
+			mu.Lock()
+			regions[awssdk.StringValue(b.Name)] = region
+			mu.Unlock()
+		}(b)
+	}
+	wg.Wait()
+	close(errc)
+
+	for err := range errc {
+		if err != nil {
+			return regions, err
+		}
+	}
+
+	return regions, nil
 }
 
 func (s *Storage) getBucketsPerRegion() ([]*s3.Bucket, error) {
@@ -216,6 +1217,13 @@ func (s *Storage) getBucketsPerRegion() ([]*s3.Bucket, error) {
 		return buckets, err
 	}
 
+	if s.config.enabled() {
+		// S3-compatible servers routinely return an empty or non-AWS
+		// LocationConstraint, so GetBucketLocation can't be trusted here:
+		// treat every bucket ListBuckets returned as in-region.
+		return out.Buckets, nil
+	}
+
 	bucketc := make(chan *s3.Bucket)
 	errc := make(chan error)
 
@@ -260,6 +1268,11 @@ func (s *Storage) getBucketsPerRegion() ([]*s3.Bucket, error) {
 	}
 }
 
+// defaultBucketFetchConcurrency bounds how many buckets are processed at
+// once by foreach_bucket_parallel, so very wide accounts don't exhaust
+// file descriptors on the underlying HTTP transport.
+const defaultBucketFetchConcurrency = 16
+
 func (s *Storage) foreach_bucket_parallel(f func(b *s3.Bucket) error) error {
 	s.once.Do(func() {
 		s.once.result, s.once.err = s.getBucketsPerRegion()
@@ -270,12 +1283,15 @@ func (s *Storage) foreach_bucket_parallel(f func(b *s3.Bucket) error) error {
 	buckets := s.once.result.([]*s3.Bucket)
 
 	errc := make(chan error)
+	sem := make(chan struct{}, defaultBucketFetchConcurrency)
 	var wg sync.WaitGroup
 
 	for _, output := range buckets {
 		wg.Add(1)
 		go func(b *s3.Bucket) {
 			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
 			if err := f(b); err != nil {
 				errc <- err
 			}
@@ -0,0 +1,93 @@
+/*
+Copyright 2017 WALLIX
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	awssdk "github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+)
+
+type fakeBucketLocationAPI struct {
+	s3iface.S3API
+	inFlight          int32
+	maxInFlight       int32
+	locationsByBucket map[string]string
+}
+
+func (f *fakeBucketLocationAPI) GetBucketLocation(in *s3.GetBucketLocationInput) (*s3.GetBucketLocationOutput, error) {
+	cur := atomic.AddInt32(&f.inFlight, 1)
+	defer atomic.AddInt32(&f.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&f.maxInFlight)
+		if cur <= max {
+			break
+		}
+		if atomic.CompareAndSwapInt32(&f.maxInFlight, max, cur) {
+			break
+		}
+	}
+
+	region := f.locationsByBucket[awssdk.StringValue(in.Bucket)]
+	return &s3.GetBucketLocationOutput{LocationConstraint: awssdk.String(region)}, nil
+}
+
+func TestResolveBucketRegionsBoundsConcurrency(t *testing.T) {
+	fake := &fakeBucketLocationAPI{locationsByBucket: make(map[string]string)}
+	var buckets []*s3.Bucket
+	for i := 0; i < 20; i++ {
+		name := fmt.Sprintf("bucket-%d", i)
+		fake.locationsByBucket[name] = "eu-west-1"
+		buckets = append(buckets, &s3.Bucket{Name: awssdk.String(name)})
+	}
+
+	s := &Storage{S3API: fake}
+
+	const concurrency = 3
+	regions, err := s.resolveBucketRegions(buckets, concurrency)
+	if err != nil {
+		t.Fatalf("resolveBucketRegions: %s", err)
+	}
+	if len(regions) != len(buckets) {
+		t.Fatalf("got %d regions, want %d", len(regions), len(buckets))
+	}
+	for _, b := range buckets {
+		if got, want := regions[awssdk.StringValue(b.Name)], "eu-west-1"; got != want {
+			t.Errorf("region for %s = %q, want %q", awssdk.StringValue(b.Name), got, want)
+		}
+	}
+	if max := atomic.LoadInt32(&fake.maxInFlight); max > concurrency {
+		t.Errorf("max concurrent GetBucketLocation calls = %d, want <= %d", max, concurrency)
+	}
+}
+
+func TestResolveBucketRegionsDefaultsEmptyConstraintToUsEast1(t *testing.T) {
+	fake := &fakeBucketLocationAPI{locationsByBucket: map[string]string{"b": ""}}
+	s := &Storage{S3API: fake}
+
+	regions, err := s.resolveBucketRegions([]*s3.Bucket{{Name: awssdk.String("b")}}, 1)
+	if err != nil {
+		t.Fatalf("resolveBucketRegions: %s", err)
+	}
+	if got, want := regions["b"], "us-east-1"; got != want {
+		t.Errorf("region = %q, want %q", got, want)
+	}
+}
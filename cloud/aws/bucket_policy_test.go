@@ -0,0 +1,138 @@
+/*
+Copyright 2017 WALLIX
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+)
+
+func TestAwsPolicyDocumentUnmarshalsStringPrincipal(t *testing.T) {
+	raw := `{
+		"Version": "2012-10-17",
+		"Statement": [{"Effect": "Allow", "Principal": "*", "Action": "s3:GetObject", "Resource": "arn:aws:s3:::bucket/*"}]
+	}`
+
+	var doc awsPolicyDocument
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("unmarshal: %s", err)
+	}
+	if len(doc.Statement) != 1 {
+		t.Fatalf("got %d statements, want 1", len(doc.Statement))
+	}
+	if got, want := doc.Statement[0].Principal, "*"; got != want {
+		t.Errorf("Principal = %v, want %v", got, want)
+	}
+}
+
+func TestAwsPolicyDocumentUnmarshalsObjectPrincipal(t *testing.T) {
+	raw := `{
+		"Version": "2012-10-17",
+		"Statement": [{
+			"Effect": "Allow",
+			"Principal": {"AWS": ["arn:aws:iam::111111111111:root", "arn:aws:iam::222222222222:root"]},
+			"Action": ["s3:GetObject", "s3:PutObject"],
+			"Resource": ["arn:aws:s3:::bucket/*"],
+			"Condition": {"StringEquals": {"s3:x-amz-acl": "public-read"}}
+		}]
+	}`
+
+	var doc awsPolicyDocument
+	if err := json.Unmarshal([]byte(raw), &doc); err != nil {
+		t.Fatalf("unmarshal: %s", err)
+	}
+	if len(doc.Statement) != 1 {
+		t.Fatalf("got %d statements, want 1", len(doc.Statement))
+	}
+
+	principal, ok := doc.Statement[0].Principal.(map[string]interface{})
+	if !ok {
+		t.Fatalf("Principal = %#v, want map[string]interface{}", doc.Statement[0].Principal)
+	}
+	if _, ok := principal["AWS"]; !ok {
+		t.Errorf("Principal missing AWS key: %#v", principal)
+	}
+
+	if doc.Statement[0].Condition == nil {
+		t.Error("Condition should be populated")
+	}
+}
+
+func TestAwsPolicyDocumentRejectsMalformedJSON(t *testing.T) {
+	var doc awsPolicyDocument
+	if err := json.Unmarshal([]byte("not json"), &doc); err == nil {
+		t.Error("expected an error for malformed policy JSON")
+	}
+}
+
+type fakeErrCode string
+
+func (e fakeErrCode) Error() string   { return string(e) }
+func (e fakeErrCode) Code() string    { return string(e) }
+func (e fakeErrCode) Message() string { return string(e) }
+func (e fakeErrCode) OrigErr() error  { return nil }
+
+var _ awserr.Error = fakeErrCode("")
+
+func TestIsAbsentBucketSubResourceErr(t *testing.T) {
+	for _, code := range []string{
+		"NoSuchBucketPolicy",
+		"NoSuchCORSConfiguration",
+		"NoSuchLifecycleConfiguration",
+		"ServerSideEncryptionConfigurationNotFoundError",
+	} {
+		if !isAbsentBucketSubResourceErr(fakeErrCode(code)) {
+			t.Errorf("%s should be treated as an absent sub-resource", code)
+		}
+	}
+
+	for _, code := range []string{"AccessDenied", "AccessDeniedException"} {
+		if isAbsentBucketSubResourceErr(fakeErrCode(code)) {
+			t.Errorf("%s should not be treated as an absent sub-resource", code)
+		}
+	}
+
+	if isAbsentBucketSubResourceErr(fakeErrCode("InternalError")) {
+		t.Error("InternalError should not be treated as an absent sub-resource")
+	}
+
+	if isAbsentBucketSubResourceErr(errNotAWS{}) {
+		t.Error("a non-awserr.Error should not be treated as an absent sub-resource")
+	}
+}
+
+func TestIsAccessDeniedBucketSubResourceErr(t *testing.T) {
+	for _, code := range []string{"AccessDenied", "AccessDeniedException"} {
+		if !isAccessDeniedBucketSubResourceErr(fakeErrCode(code)) {
+			t.Errorf("%s should be treated as access-denied", code)
+		}
+	}
+
+	if isAccessDeniedBucketSubResourceErr(fakeErrCode("NoSuchBucketPolicy")) {
+		t.Error("NoSuchBucketPolicy should not be treated as access-denied")
+	}
+
+	if isAccessDeniedBucketSubResourceErr(errNotAWS{}) {
+		t.Error("a non-awserr.Error should not be treated as access-denied")
+	}
+}
+
+type errNotAWS struct{}
+
+func (errNotAWS) Error() string { return "boom" }
@@ -0,0 +1,67 @@
+/*
+Copyright 2017 WALLIX
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+func TestProfileURIScheme(t *testing.T) {
+	cases := map[string]string{
+		"iam-ibm://some-api-key":   "iam-ibm://",
+		"sso://some-profile":       "sso://",
+		"process:///usr/bin/creds": "process://",
+		"no-scheme-here":           "no-scheme-here",
+	}
+	for uri, want := range cases {
+		if got := profileURIScheme(uri); got != want {
+			t.Errorf("profileURIScheme(%q) = %q, want %q", uri, got, want)
+		}
+	}
+}
+
+type fakeCredentialProvider struct {
+	scheme   string
+	resolved string
+}
+
+func (f *fakeCredentialProvider) Scheme() string { return f.scheme }
+
+func (f *fakeCredentialProvider) ResolveSession(profileURI string) (*session.Session, error) {
+	f.resolved = profileURI
+	return session.NewSession()
+}
+
+func TestResolveSessionDispatchesByScheme(t *testing.T) {
+	fake := &fakeCredentialProvider{scheme: "test-scheme://"}
+	RegisterCredentialProvider(fake)
+
+	if _, err := ResolveSession("test-scheme://profile-a"); err != nil {
+		t.Fatalf("ResolveSession: %s", err)
+	}
+	if fake.resolved != "test-scheme://profile-a" {
+		t.Errorf("registered provider was called with %q, want %q", fake.resolved, "test-scheme://profile-a")
+	}
+}
+
+func TestResolveSessionUnknownScheme(t *testing.T) {
+	if _, err := ResolveSession("nobody-registered-this://profile"); err == nil {
+		t.Error("expected an error for an unregistered scheme")
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2017 WALLIX
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package aws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3iface"
+	"github.com/wallix/awless/graph"
+)
+
+func TestStorageFetchOptionsKeepsGoing(t *testing.T) {
+	unbounded := StorageFetchOptions{}
+	if !unbounded.keepsGoing(1000) {
+		t.Error("MaxKeysPerBucket unset should never stop pagination")
+	}
+
+	bounded := StorageFetchOptions{MaxKeysPerBucket: 3}
+	if !bounded.keepsGoing(2) {
+		t.Error("count below MaxKeysPerBucket should keep going")
+	}
+	if bounded.keepsGoing(3) {
+		t.Error("count reaching MaxKeysPerBucket should stop")
+	}
+}
+
+func TestStorageFetchOptionsSkips(t *testing.T) {
+	since := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := StorageFetchOptions{SinceModified: since}
+
+	older := since.Add(-time.Hour)
+	if !opts.skips(&older) {
+		t.Error("object modified before SinceModified should be skipped")
+	}
+
+	newer := since.Add(time.Hour)
+	if opts.skips(&newer) {
+		t.Error("object modified after SinceModified should not be skipped")
+	}
+
+	if opts.skips(nil) {
+		t.Error("a nil LastModified should never be skipped")
+	}
+
+	if (StorageFetchOptions{}).skips(&older) {
+		t.Error("a zero SinceModified should never skip")
+	}
+}
+
+// fakeObjectPager implements s3iface.S3API's ListObjectsV2Pages just
+// enough to exercise fetchObjectsForBucketIn's stop condition: it feeds
+// pages one at a time and records how many it was allowed to serve before
+// the callback returned false.
+type fakeObjectPager struct {
+	s3iface.S3API
+	pages       []*s3.ListObjectsV2Output
+	pagesServed int
+}
+
+func (f *fakeObjectPager) ListObjectsV2Pages(in *s3.ListObjectsV2Input, fn func(*s3.ListObjectsV2Output, bool) bool) error {
+	for i, page := range f.pages {
+		f.pagesServed++
+		if !fn(page, i == len(f.pages)-1) {
+			break
+		}
+	}
+	return nil
+}
+
+func TestFetchObjectsForBucketInStopsAtMaxKeysPerBucket(t *testing.T) {
+	fake := &fakeObjectPager{pages: []*s3.ListObjectsV2Output{
+		{Contents: []*s3.Object{{Key: stringPtr("a")}, {Key: stringPtr("b")}}},
+		{Contents: []*s3.Object{{Key: stringPtr("c")}, {Key: stringPtr("d")}}},
+		{Contents: []*s3.Object{{Key: stringPtr("e")}, {Key: stringPtr("f")}}},
+	}}
+
+	fetchObjectsForBucketIn(fake, &s3.Bucket{Name: stringPtr("bucket")}, graph.NewGraph(), StorageFetchOptions{MaxKeysPerBucket: 3})
+
+	if fake.pagesServed != 2 {
+		t.Errorf("pagesServed = %d, want 2 (stop partway through the 2nd page)", fake.pagesServed)
+	}
+}
+
+func stringPtr(s string) *string { return &s }